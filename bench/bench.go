@@ -1,9 +1,21 @@
 package bench
 
 import (
+	"bufio"
+	"encoding/json"
 	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
 	"runtime"
+	"runtime/debug"
+	"runtime/pprof"
 	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -15,11 +27,71 @@ type BenchmarkResult struct {
 	AverageTime time.Duration
 	MemoryUsage uint64
 	AllocCount  uint64
+
+	// Per-iteration timing distribution. Only meaningful when
+	// HasDistribution is true: RunParallel has no per-iteration
+	// measurements to draw these from, and leaves them at their zero value
+	// with HasDistribution false rather than reporting a misleading "zero
+	// jitter".
+	HasDistribution bool
+	MinTime         time.Duration
+	MaxTime         time.Duration
+	StdDevTime      time.Duration
+	P50Time         time.Duration
+	P90Time         time.Duration
+	P99Time         time.Duration
+	AllocStdDev     float64
+
+	// FlakeRuns and FlakeCV are only populated by RunFlaky: FlakeRuns is the
+	// number of independent repeats the benchmark was run, and FlakeCV is the
+	// coefficient of variation (stddev/mean) of AverageTime across those
+	// repeats. FlakeRuns is 0 if the result wasn't produced by RunFlaky.
+	FlakeRuns int
+	FlakeCV   float64
+
+	// GOMAXPROCS is the value of runtime.GOMAXPROCS(0) in effect when this
+	// result was produced, used as the "-N" suffix in Go benchmark format.
+	GOMAXPROCS int
+
+	// Profile paths, populated by RunT when the corresponding WithXProfile
+	// option is set. Empty if that profile wasn't captured.
+	CPUProfilePath   string
+	MemProfilePath   string
+	BlockProfilePath string
 }
 
 // Benchmark is the main benchmarking utility
 type Benchmark struct {
 	results []BenchmarkResult
+
+	cpuProfileDir    string
+	memProfileDir    string
+	blockProfileDir  string
+	blockProfileRate int
+}
+
+// WithCPUProfile makes every subsequent RunT (and Run, which is built on
+// it) write a CPU profile for its measured iterations to
+// dir/<name>.cpu.pprof.
+func (b *Benchmark) WithCPUProfile(dir string) *Benchmark {
+	b.cpuProfileDir = dir
+	return b
+}
+
+// WithMemProfile makes every subsequent RunT (and Run) write a heap profile
+// taken right after its measured iterations to dir/<name>.heap.pprof.
+func (b *Benchmark) WithMemProfile(dir string) *Benchmark {
+	b.memProfileDir = dir
+	return b
+}
+
+// WithBlockProfile makes every subsequent RunT (and Run) record blocking
+// events at the given rate (see runtime.SetBlockProfileRate) during its
+// measured iterations and write them to dir/<name>.block.pprof.
+func (b *Benchmark) WithBlockProfile(dir string, rate int) *Benchmark {
+	b.blockProfileDir = dir
+	b.blockProfileRate = rate
+	return b
 }
 
 // measurement represents a single iteration measurement
@@ -36,74 +108,561 @@ func New() *Benchmark {
 	}
 }
 
-// Run executes a single benchmark
+// Run executes a single benchmark. fn runs under an always-on timer, so all
+// of its time and allocations count toward the measurement; use RunT if fn
+// needs to exclude setup cost.
 func (b *Benchmark) Run(name string, fn func(), iterations int) *Benchmark {
+	return b.RunT(name, func(t *Timer) { fn() }, iterations)
+}
+
+// RunT executes a single benchmark, passing fn a *Timer it can use to
+// exclude setup cost (via StopTimer/StartTimer/ResetTimer) from the
+// measured time and allocations. If WithCPUProfile, WithMemProfile, and/or
+// WithBlockProfile were called, the corresponding profiles are captured
+// around the measured iterations (not the warm-up run).
+func (b *Benchmark) RunT(name string, fn func(t *Timer), iterations int) *Benchmark {
+	fmt.Printf("Starting: %s\n", name)
+
+	profiler := newProfileCapture(b, name)
+	measurements := runIterations(fn, iterations, profiler.start, profiler.stop)
+
+	result := summarize(name, iterations, measurements)
+	result.CPUProfilePath = profiler.cpuPath
+	result.MemProfilePath = profiler.memPath
+	result.BlockProfilePath = profiler.blockPath
+	b.results = append(b.results, result)
+
+	fmt.Printf("Done: %s\n", name)
+	return b
+}
+
+// Compare runs multiple benchmarks
+func (b *Benchmark) Compare(tests map[string]func(), iterations int) *Benchmark {
+	for name, fn := range tests {
+		b.Run(name, fn, iterations)
+	}
+	return b
+}
+
+// RunFor executes a benchmark for at least the given duration, mirroring
+// `go test -benchtime`. benchtime accepts a duration string (e.g. "1s") to
+// auto-scale the iteration count until the run takes at least that long, or
+// an "Nx" form (e.g. "100x") to run exactly N iterations.
+func (b *Benchmark) RunFor(name string, fn func(), benchtime string) *Benchmark {
 	fmt.Printf("Starting: %s\n", name)
 
+	target, fixedN, isFixed, err := parseBenchtime(benchtime)
+	if err != nil {
+		panic(fmt.Sprintf("bench: invalid benchtime %q: %v", benchtime, err))
+	}
+
+	timedFn := func(t *Timer) { fn() }
+
+	var iterations int
+	var measurements []measurement
+
+	if isFixed {
+		iterations = fixedN
+		measurements = runIterations(timedFn, iterations, nil, nil)
+	} else {
+		iterations = 1
+		for {
+			wallStart := time.Now()
+			measurements = runIterations(timedFn, iterations, nil, nil)
+			wallElapsed := time.Since(wallStart)
+
+			if wallElapsed >= target {
+				break
+			}
+
+			iterations = nextIterationCount(iterations, wallElapsed, target)
+		}
+	}
+
+	b.results = append(b.results, summarize(name, iterations, measurements))
+
+	fmt.Printf("Done: %s\n", name)
+	return b
+}
+
+// CompareFor runs multiple benchmarks, each for the given benchtime.
+func (b *Benchmark) CompareFor(tests map[string]func(), benchtime string) *Benchmark {
+	for name, fn := range tests {
+		b.RunFor(name, fn, benchtime)
+	}
+	return b
+}
+
+// runIterations runs fn for the given number of iterations (after a warm-up
+// run and a forced GC) and returns one measurement per iteration. Each
+// iteration gets its own Timer, started automatically before fn runs; only
+// time and allocations that occur while the timer is running are counted.
+// beforeMeasure and afterMeasure, if non-nil, bracket the measured
+// iterations (not the warm-up run) and are used to scope profiling.
+func runIterations(fn func(t *Timer), iterations int, beforeMeasure, afterMeasure func()) []measurement {
 	// Force GC before starting
 	runtime.GC()
 
 	// Warm up run
-	fn()
+	warmup := &Timer{}
+	warmup.StartTimer()
+	fn(warmup)
+
+	if beforeMeasure != nil {
+		beforeMeasure()
+	}
 
 	measurements := make([]measurement, iterations)
 
 	for i := 0; i < iterations; i++ {
-		// Get memory stats before
-		var memStatsBefore runtime.MemStats
-		runtime.ReadMemStats(&memStatsBefore)
+		t := &Timer{}
+		t.StartTimer()
+		fn(t)
+		t.StopTimer()
 
-		start := time.Now()
-		fn()
-		duration := time.Since(start)
+		measurements[i] = measurement{
+			duration:    t.elapsed,
+			memoryDelta: t.memoryDelta,
+			allocCount:  t.allocCount,
+		}
+	}
 
-		// Get memory stats after
-		var memStatsAfter runtime.MemStats
-		runtime.ReadMemStats(&memStatsAfter)
+	if afterMeasure != nil {
+		afterMeasure()
+	}
 
-		measurements[i] = measurement{
-			duration:    duration,
-			memoryDelta: memStatsAfter.HeapAlloc - memStatsBefore.HeapAlloc,
-			allocCount:  memStatsAfter.Mallocs - memStatsBefore.Mallocs,
+	return measurements
+}
+
+// profileCapture scopes CPU, heap, and block profile collection to a single
+// RunT call's measured iterations, per the Benchmark's WithXProfile
+// settings.
+type profileCapture struct {
+	b    *Benchmark
+	name string
+
+	cpuFile *os.File
+
+	cpuPath   string
+	memPath   string
+	blockPath string
+}
+
+func newProfileCapture(b *Benchmark, name string) *profileCapture {
+	return &profileCapture{b: b, name: name}
+}
+
+// start begins CPU and block profiling, if configured. It runs right after
+// the warm-up iteration, before the measured loop.
+func (p *profileCapture) start() {
+	if p.b.cpuProfileDir != "" {
+		path := filepath.Join(p.b.cpuProfileDir, p.name+".cpu.pprof")
+		if f, err := os.Create(path); err == nil {
+			if err := pprof.StartCPUProfile(f); err == nil {
+				p.cpuFile = f
+				p.cpuPath = path
+			} else {
+				f.Close()
+			}
+		}
+	}
+
+	if p.b.blockProfileDir != "" {
+		runtime.SetBlockProfileRate(p.b.blockProfileRate)
+	}
+}
+
+// stop ends CPU and block profiling and writes the heap profile, if
+// configured. It runs right after the measured loop.
+func (p *profileCapture) stop() {
+	if p.cpuFile != nil {
+		pprof.StopCPUProfile()
+		p.cpuFile.Close()
+	}
+
+	if p.b.memProfileDir != "" {
+		runtime.GC()
+		path := filepath.Join(p.b.memProfileDir, p.name+".heap.pprof")
+		if f, err := os.Create(path); err == nil {
+			if err := pprof.Lookup("heap").WriteTo(f, 0); err == nil {
+				p.memPath = path
+			}
+			f.Close()
+		}
+	}
+
+	if p.b.blockProfileDir != "" {
+		path := filepath.Join(p.b.blockProfileDir, p.name+".block.pprof")
+		if f, err := os.Create(path); err == nil {
+			if err := pprof.Lookup("block").WriteTo(f, 0); err == nil {
+				p.blockPath = path
+			}
+			f.Close()
 		}
+		runtime.SetBlockProfileRate(0)
+	}
+}
+
+// memStatDelta returns after-before, clamped to 0. runtime.MemStats counters
+// like HeapAlloc can decrease between two reads (background scavenging/GC),
+// and these are unsigned, so a naive subtraction would wrap around to a
+// huge bogus value.
+func memStatDelta(after, before uint64) uint64 {
+	if after < before {
+		return 0
+	}
+	return after - before
+}
+
+// Timer gates how much of a benchmark iteration's wall time, HeapAlloc
+// delta, and Mallocs delta count toward its measurement. It is passed to
+// benchmark functions registered via RunT; StopTimer/StartTimer exclude
+// setup cost from the measurement, and ResetTimer discards what's
+// accumulated so far without affecting the running/stopped state.
+type Timer struct {
+	running     bool
+	elapsed     time.Duration
+	memoryDelta uint64
+	allocCount  uint64
+
+	segStart    time.Time
+	segMemStats runtime.MemStats
+}
+
+// StartTimer resumes accumulating time and allocations. It is a no-op if
+// the timer is already running.
+func (t *Timer) StartTimer() {
+	if t.running {
+		return
+	}
+	t.running = true
+	runtime.ReadMemStats(&t.segMemStats)
+	t.segStart = time.Now()
+}
+
+// StopTimer pauses accumulation of time and allocations. It is a no-op if
+// the timer is already stopped.
+func (t *Timer) StopTimer() {
+	if !t.running {
+		return
+	}
+
+	t.elapsed += time.Since(t.segStart)
+
+	var after runtime.MemStats
+	runtime.ReadMemStats(&after)
+	t.memoryDelta += memStatDelta(after.HeapAlloc, t.segMemStats.HeapAlloc)
+	t.allocCount += memStatDelta(after.Mallocs, t.segMemStats.Mallocs)
+
+	t.running = false
+}
+
+// ResetTimer discards everything accumulated so far. If the timer is
+// currently running, accumulation continues from this point on.
+func (t *Timer) ResetTimer() {
+	t.elapsed = 0
+	t.memoryDelta = 0
+	t.allocCount = 0
+
+	if t.running {
+		runtime.ReadMemStats(&t.segMemStats)
+		t.segStart = time.Now()
 	}
+}
 
-	// Calculate averages
-	var totalDuration time.Duration
+// summarize reduces a set of per-iteration measurements into a BenchmarkResult.
+func summarize(name string, iterations int, measurements []measurement) BenchmarkResult {
 	var totalMemory uint64
 	var totalAllocs uint64
 
+	totalDur := totalDuration(measurements)
 	for _, m := range measurements {
-		totalDuration += m.duration
 		totalMemory += m.memoryDelta
 		totalAllocs += m.allocCount
 	}
 
-	avgDuration := totalDuration / time.Duration(iterations)
+	avgDuration := totalDur / time.Duration(iterations)
 	avgMemory := totalMemory / uint64(iterations)
 	avgAllocs := totalAllocs / uint64(iterations)
 
+	durations := make([]time.Duration, len(measurements))
+	allocCounts := make([]float64, len(measurements))
+	for i, m := range measurements {
+		durations[i] = m.duration
+		allocCounts[i] = float64(m.allocCount)
+	}
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+
+	var minTime, maxTime time.Duration
+	if len(durations) > 0 {
+		minTime = durations[0]
+		maxTime = durations[len(durations)-1]
+	}
+
+	_, allocStdDev := meanStdDev(allocCounts)
+
+	return BenchmarkResult{
+		Name:            name,
+		TotalTime:       totalDur,
+		Iterations:      iterations,
+		AverageTime:     avgDuration,
+		MemoryUsage:     avgMemory,
+		AllocCount:      avgAllocs,
+		HasDistribution: true,
+		MinTime:         minTime,
+		MaxTime:         maxTime,
+		StdDevTime:      durationStdDev(durations, avgDuration),
+		P50Time:         percentile(durations, 50),
+		P90Time:         percentile(durations, 90),
+		P99Time:         percentile(durations, 99),
+		AllocStdDev:     allocStdDev,
+		GOMAXPROCS:      runtime.GOMAXPROCS(0),
+	}
+}
+
+// durationStdDev returns the population standard deviation of durations
+// around mean.
+func durationStdDev(durations []time.Duration, mean time.Duration) time.Duration {
+	if len(durations) == 0 {
+		return 0
+	}
+
+	var sqDiffSum float64
+	for _, d := range durations {
+		diff := float64(d - mean)
+		sqDiffSum += diff * diff
+	}
+	return time.Duration(math.Sqrt(sqDiffSum / float64(len(durations))))
+}
+
+// percentile returns the p-th percentile (0-100) of sorted, using the
+// nearest-rank method. sorted must already be sorted ascending.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+
+	idx := int(math.Ceil(p/100*float64(len(sorted)))) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	if idx >= len(sorted) {
+		idx = len(sorted) - 1
+	}
+	return sorted[idx]
+}
+
+// meanStdDev returns the mean and population standard deviation of values.
+func meanStdDev(values []float64) (mean, stddev float64) {
+	if len(values) == 0 {
+		return 0, 0
+	}
+
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	mean = sum / float64(len(values))
+
+	var sqDiffSum float64
+	for _, v := range values {
+		diff := v - mean
+		sqDiffSum += diff * diff
+	}
+	stddev = math.Sqrt(sqDiffSum / float64(len(values)))
+
+	return mean, stddev
+}
+
+// RunFlaky re-runs a benchmark `runs` independent times to gauge stability.
+// It reports the coefficient of variation (stddev/mean) of the per-run
+// average time across those repeats in the result's FlakeCV field, so a
+// caller can tell whether a benchmark is stable enough to trust before
+// reading too much into the "X is N.Nx slower" comparison in PrintResults.
+func (b *Benchmark) RunFlaky(name string, fn func(), iterations int, runs int) *Benchmark {
+	fmt.Printf("Starting: %s (flake check, %d runs)\n", name, runs)
+
+	timedFn := func(t *Timer) { fn() }
+
+	var allMeasurements []measurement
+	avgTimes := make([]float64, runs)
+
+	for i := 0; i < runs; i++ {
+		m := runIterations(timedFn, iterations, nil, nil)
+		allMeasurements = append(allMeasurements, m...)
+		avgTimes[i] = float64(totalDuration(m)) / float64(iterations)
+	}
+
+	mean, stddev := meanStdDev(avgTimes)
+	cv := 0.0
+	if mean != 0 {
+		cv = stddev / mean
+	}
+
+	result := summarize(name, iterations*runs, allMeasurements)
+	result.FlakeRuns = runs
+	result.FlakeCV = cv
+
+	b.results = append(b.results, result)
+
+	fmt.Printf("Done: %s (CV=%.4f)\n", name, cv)
+	return b
+}
+
+// PB hands out iterations of shared work to the goroutines spawned by
+// RunParallel, analogous to testing.B's PB.
+type PB struct {
+	remaining *int64
+}
+
+// Next reports whether there is another iteration to run. It is safe to
+// call concurrently from multiple goroutines.
+func (pb *PB) Next() bool {
+	return atomic.AddInt64(pb.remaining, -1) >= 0
+}
+
+// RunParallel runs fn across runtime.GOMAXPROCS(0) goroutines that pull work
+// from a shared iteration budget via PB.Next(), analogous to
+// testing.B.RunParallel. Wall time is measured across the whole parallel
+// run, and per-goroutine allocation deltas (captured under a mutex around
+// runtime.ReadMemStats) are summed into the result.
+func (b *Benchmark) RunParallel(name string, fn func(pb *PB), iterations int) *Benchmark {
+	fmt.Printf("Starting: %s (parallel)\n", name)
+
+	runtime.GC()
+
+	procs := runtime.GOMAXPROCS(0)
+	remaining := int64(iterations)
+
+	var wg sync.WaitGroup
+	var statsMu sync.Mutex
+	var totalMemDelta uint64
+	var totalAllocDelta uint64
+
+	start := time.Now()
+	for i := 0; i < procs; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			statsMu.Lock()
+			var before runtime.MemStats
+			runtime.ReadMemStats(&before)
+			statsMu.Unlock()
+
+			pb := &PB{remaining: &remaining}
+			for pb.Next() {
+				fn(pb)
+			}
+
+			statsMu.Lock()
+			var after runtime.MemStats
+			runtime.ReadMemStats(&after)
+			totalMemDelta += memStatDelta(after.HeapAlloc, before.HeapAlloc)
+			totalAllocDelta += memStatDelta(after.Mallocs, before.Mallocs)
+			statsMu.Unlock()
+		}()
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
 	b.results = append(b.results, BenchmarkResult{
 		Name:        name,
-		TotalTime:   totalDuration,
+		TotalTime:   elapsed,
 		Iterations:  iterations,
-		AverageTime: avgDuration,
-		MemoryUsage: avgMemory,
-		AllocCount:  avgAllocs,
+		AverageTime: elapsed / time.Duration(iterations),
+		MemoryUsage: totalMemDelta / uint64(iterations),
+		AllocCount:  totalAllocDelta / uint64(iterations),
+		GOMAXPROCS:  procs,
 	})
 
 	fmt.Printf("Done: %s\n", name)
 	return b
 }
 
-// Compare runs multiple benchmarks
-func (b *Benchmark) Compare(tests map[string]func(), iterations int) *Benchmark {
-	for name, fn := range tests {
-		b.Run(name, fn, iterations)
+// SweepProcs re-runs a benchmark once per GOMAXPROCS value in procs, so
+// callers can see how a workload scales with available parallelism. Each
+// run is recorded as its own BenchmarkResult named "name-P" (e.g. "name-4").
+// GOMAXPROCS is restored to its original value before SweepProcs returns.
+func (b *Benchmark) SweepProcs(name string, fn func(), iterations int, procs []int) *Benchmark {
+	original := runtime.GOMAXPROCS(0)
+	defer runtime.GOMAXPROCS(original)
+
+	for _, p := range procs {
+		runtime.GOMAXPROCS(p)
+		b.Run(fmt.Sprintf("%s-%d", name, p), fn, iterations)
 	}
+
 	return b
 }
 
+// totalDuration sums the wall-clock duration across measurements.
+func totalDuration(measurements []measurement) time.Duration {
+	var total time.Duration
+	for _, m := range measurements {
+		total += m.duration
+	}
+	return total
+}
+
+// parseBenchtime parses a benchtime spec as either a duration (e.g. "1s") or
+// an "Nx" fixed iteration count (e.g. "100x"), matching `go test -benchtime`.
+func parseBenchtime(spec string) (d time.Duration, n int, isFixed bool, err error) {
+	if strings.HasSuffix(spec, "x") {
+		rest := strings.TrimSuffix(spec, "x")
+		count, convErr := strconv.Atoi(strings.TrimSpace(rest))
+		if convErr != nil || count <= 0 {
+			return 0, 0, false, fmt.Errorf("expected positive integer before 'x'")
+		}
+		return 0, count, true, nil
+	}
+
+	dur, convErr := time.ParseDuration(spec)
+	if convErr != nil || dur <= 0 {
+		return 0, 0, false, fmt.Errorf("expected a duration or \"Nx\" iteration count")
+	}
+	return dur, 0, false, nil
+}
+
+// nextIterationCount computes the next iteration count to try when the
+// previous run of prevN iterations finished in elapsed but target hasn't
+// been reached yet. It scales prevN by target/elapsed, rounds up to the next
+// 1/2/5*10^k step, never shrinks, and caps growth at 100x the previous N.
+func nextIterationCount(prevN int, elapsed, target time.Duration) int {
+	scaled := float64(target) * float64(prevN) / float64(elapsed)
+
+	next := roundUp125(int64(scaled))
+	if next <= int64(prevN) {
+		next = roundUp125(int64(prevN) + 1)
+	}
+
+	if max := int64(prevN) * 100; next > max {
+		next = max
+	}
+
+	return int(next)
+}
+
+// roundUp125 rounds n up to the nearest value of the form {1,2,5}*10^k.
+func roundUp125(n int64) int64 {
+	if n <= 1 {
+		return 1
+	}
+
+	step := int64(1)
+	for step*10 <= n {
+		step *= 10
+	}
+
+	for _, m := range []int64{1, 2, 5, 10} {
+		if candidate := step * m; candidate >= n {
+			return candidate
+		}
+	}
+
+	return step * 10
+}
+
 // PrintResults displays the benchmark results in a formatted table
 func (b *Benchmark) PrintResults() {
 	fmt.Println("\nBenchmark Results:")
@@ -120,6 +679,40 @@ func (b *Benchmark) PrintResults() {
 			result.AllocCount)
 	}
 
+	fmt.Println("\nLatency Distribution:")
+	fmt.Printf("%-20s %-12s %-12s %-12s %-12s %-12s %-12s %-12s\n",
+		"Test Name", "Min", "Max", "StdDev", "p50", "p90", "p99", "AllocStdDev")
+	for _, result := range b.results {
+		if !result.HasDistribution {
+			fmt.Printf("%-20s %s\n", result.Name, "n/a (no per-iteration measurements, e.g. RunParallel)")
+			continue
+		}
+
+		fmt.Printf("%-20s %-12s %-12s %-12s %-12s %-12s %-12s %-12.2f\n",
+			result.Name,
+			result.MinTime,
+			result.MaxTime,
+			result.StdDevTime,
+			result.P50Time,
+			result.P90Time,
+			result.P99Time,
+			result.AllocStdDev)
+
+		if result.FlakeRuns > 0 {
+			fmt.Printf("%-20s CV=%.4f across %d runs", result.Name, result.FlakeCV, result.FlakeRuns)
+			if result.FlakeCV > 0.05 {
+				fmt.Printf(" (unstable, treat comparisons with caution)")
+			}
+			fmt.Println()
+		}
+
+		if result.CPUProfilePath != "" || result.MemProfilePath != "" || result.BlockProfilePath != "" {
+			fmt.Printf("%-20s profiles: cpu=%s mem=%s block=%s\n",
+				result.Name, profilePathOrNone(result.CPUProfilePath),
+				profilePathOrNone(result.MemProfilePath), profilePathOrNone(result.BlockProfilePath))
+		}
+	}
+
 	// Find and report the fastest test
 	sort.Slice(b.results, func(i, j int) bool {
 		return b.results[i].AverageTime < b.results[j].AverageTime
@@ -135,6 +728,89 @@ func (b *Benchmark) PrintResults() {
 	}
 }
 
+// WriteBenchmarkFormat writes the results in the standard Go benchmark text
+// format understood by benchstat and perf.golang.org: a header block
+// (goos/goarch/pkg/cpu) followed by one "BenchmarkName-GOMAXPROCS" line per
+// result.
+func (b *Benchmark) WriteBenchmarkFormat(w io.Writer) error {
+	if _, err := fmt.Fprintf(w, "goos: %s\n", runtime.GOOS); err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "goarch: %s\n", runtime.GOARCH); err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "pkg: %s\n", pkgPath()); err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "cpu: %s\n", cpuModel()); err != nil {
+		return err
+	}
+
+	for _, result := range b.results {
+		_, err := fmt.Fprintf(w, "Benchmark%s-%d\t%d\t%d ns/op\t%d B/op\t%d allocs/op\n",
+			result.Name,
+			result.GOMAXPROCS,
+			result.Iterations,
+			result.AverageTime.Nanoseconds(),
+			result.MemoryUsage,
+			result.AllocCount)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WriteJSON writes the results as a JSON array for structured consumption.
+func (b *Benchmark) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(b.results)
+}
+
+// pkgPath returns the importable path of the running binary's main module,
+// falling back to "unknown" when build info isn't available (e.g. a binary
+// built without modules).
+func pkgPath() string {
+	if bi, ok := debug.ReadBuildInfo(); ok && bi.Main.Path != "" {
+		return bi.Main.Path
+	}
+	return "unknown"
+}
+
+// cpuModel returns a human-readable CPU model string, read from
+// /proc/cpuinfo on Linux. It falls back to a runtime.NumCPU-based description
+// when /proc/cpuinfo isn't available or has no "model name" line.
+func cpuModel() string {
+	f, err := os.Open("/proc/cpuinfo")
+	if err != nil {
+		return fmt.Sprintf("%d CPU(s)", runtime.NumCPU())
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "model name") {
+			continue
+		}
+		if idx := strings.Index(line, ":"); idx >= 0 {
+			return strings.TrimSpace(line[idx+1:])
+		}
+	}
+
+	return fmt.Sprintf("%d CPU(s)", runtime.NumCPU())
+}
+
+// profilePathOrNone returns path, or "-" if it's empty, for table display.
+func profilePathOrNone(path string) string {
+	if path == "" {
+		return "-"
+	}
+	return path
+}
+
 // Clear resets the benchmark results
 func (b *Benchmark) Clear() *Benchmark {
 	b.results = b.results[:0]
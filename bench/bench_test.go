@@ -0,0 +1,346 @@
+package bench
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRoundUp125(t *testing.T) {
+	cases := []struct {
+		in   int64
+		want int64
+	}{
+		{0, 1},
+		{1, 1},
+		{2, 2},
+		{3, 5},
+		{5, 5},
+		{6, 10},
+		{10, 10},
+		{11, 20},
+		{42, 50},
+		{100, 100},
+		{101, 200},
+		{999, 1000},
+	}
+
+	for _, c := range cases {
+		if got := roundUp125(c.in); got != c.want {
+			t.Errorf("roundUp125(%d) = %d, want %d", c.in, got, c.want)
+		}
+	}
+}
+
+func TestNextIterationCountNeverShrinks(t *testing.T) {
+	next := nextIterationCount(10, time.Millisecond, time.Second)
+	if next <= 10 {
+		t.Errorf("nextIterationCount should grow N, got %d from prevN=10", next)
+	}
+}
+
+func TestNextIterationCountCapsGrowth(t *testing.T) {
+	// elapsed is tiny compared to target, so the raw scale factor would be
+	// enormous; growth must still be capped at 100x the previous N.
+	next := nextIterationCount(1, time.Nanosecond, time.Second)
+	if next > 100 {
+		t.Errorf("nextIterationCount(1, 1ns, 1s) = %d, want <= 100", next)
+	}
+}
+
+func TestParseBenchtimeDuration(t *testing.T) {
+	d, n, isFixed, err := parseBenchtime("250ms")
+	if err != nil {
+		t.Fatalf("parseBenchtime returned error: %v", err)
+	}
+	if isFixed {
+		t.Fatalf("expected a duration spec, got fixed iteration count %d", n)
+	}
+	if d != 250*time.Millisecond {
+		t.Errorf("got duration %v, want 250ms", d)
+	}
+}
+
+func TestParseBenchtimeFixedCount(t *testing.T) {
+	_, n, isFixed, err := parseBenchtime("100x")
+	if err != nil {
+		t.Fatalf("parseBenchtime returned error: %v", err)
+	}
+	if !isFixed {
+		t.Fatalf("expected a fixed iteration count spec")
+	}
+	if n != 100 {
+		t.Errorf("got n=%d, want 100", n)
+	}
+}
+
+func TestParseBenchtimeInvalid(t *testing.T) {
+	for _, spec := range []string{"", "0x", "-5x", "notaduration"} {
+		if _, _, _, err := parseBenchtime(spec); err == nil {
+			t.Errorf("parseBenchtime(%q) expected an error, got none", spec)
+		}
+	}
+}
+
+// TestRunForSubMicrosecondFn is a regression test: RunFor's scaling loop
+// must track real wall-clock time (including ReadMemStats overhead), not
+// just the timed fn duration, or a near-instant fn makes it chase a target
+// it can never reach and hang.
+func TestRunForSubMicrosecondFn(t *testing.T) {
+	done := make(chan struct{})
+	go func() {
+		New().RunFor("noop", func() {}, "20ms")
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("RunFor did not return within 2s for a near-instant fn")
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	sorted := []time.Duration{
+		1 * time.Millisecond,
+		2 * time.Millisecond,
+		3 * time.Millisecond,
+		4 * time.Millisecond,
+		5 * time.Millisecond,
+	}
+
+	cases := []struct {
+		p    float64
+		want time.Duration
+	}{
+		{50, 3 * time.Millisecond},
+		{90, 5 * time.Millisecond},
+		{100, 5 * time.Millisecond},
+	}
+
+	for _, c := range cases {
+		if got := percentile(sorted, c.p); got != c.want {
+			t.Errorf("percentile(sorted, %v) = %v, want %v", c.p, got, c.want)
+		}
+	}
+}
+
+func TestPercentileEmpty(t *testing.T) {
+	if got := percentile(nil, 50); got != 0 {
+		t.Errorf("percentile(nil, 50) = %v, want 0", got)
+	}
+}
+
+func TestMeanStdDev(t *testing.T) {
+	mean, stddev := meanStdDev([]float64{2, 4, 4, 4, 5, 5, 7, 9})
+	if mean != 5 {
+		t.Errorf("mean = %v, want 5", mean)
+	}
+	if diff := stddev - 2; diff < -0.001 || diff > 0.001 {
+		t.Errorf("stddev = %v, want ~2", stddev)
+	}
+}
+
+func TestMeanStdDevEmpty(t *testing.T) {
+	mean, stddev := meanStdDev(nil)
+	if mean != 0 || stddev != 0 {
+		t.Errorf("meanStdDev(nil) = (%v, %v), want (0, 0)", mean, stddev)
+	}
+}
+
+func TestWriteBenchmarkFormat(t *testing.T) {
+	b := New()
+	b.Run("Example", func() {}, 5)
+
+	var buf bytes.Buffer
+	if err := b.WriteBenchmarkFormat(&buf); err != nil {
+		t.Fatalf("WriteBenchmarkFormat returned error: %v", err)
+	}
+
+	out := buf.String()
+	for _, want := range []string{"goos:", "goarch:", "pkg:", "cpu:", "BenchmarkExample-"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	b := New()
+	b.Run("Example", func() {}, 5)
+
+	var buf bytes.Buffer
+	if err := b.WriteJSON(&buf); err != nil {
+		t.Fatalf("WriteJSON returned error: %v", err)
+	}
+
+	var results []BenchmarkResult
+	if err := json.Unmarshal(buf.Bytes(), &results); err != nil {
+		t.Fatalf("output is not valid JSON: %v", err)
+	}
+	if len(results) != 1 || results[0].Name != "Example" {
+		t.Errorf("got %+v, want one result named Example", results)
+	}
+}
+
+// TestPBNextExhaustion mirrors how RunParallel hands PB instances to its
+// worker goroutines: multiple PBs sharing one `remaining` counter. Next()
+// must return true exactly `iterations` times in total, however the calls
+// are interleaved across goroutines. Run with -race.
+func TestPBNextExhaustion(t *testing.T) {
+	const iterations = 1000
+	const workers = 8
+
+	remaining := int64(iterations)
+	var wg sync.WaitGroup
+	var trueCount int64
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			pb := &PB{remaining: &remaining}
+			for pb.Next() {
+				atomic.AddInt64(&trueCount, 1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if trueCount != iterations {
+		t.Errorf("PB.Next() returned true %d times across %d goroutines, want exactly %d", trueCount, workers, iterations)
+	}
+}
+
+func TestSweepProcs(t *testing.T) {
+	original := runtime.GOMAXPROCS(0)
+
+	b := New()
+	procs := []int{1, 2, 4}
+
+	b.SweepProcs("work", func() {}, 3, procs)
+
+	if got := runtime.GOMAXPROCS(0); got != original {
+		t.Errorf("GOMAXPROCS not restored after SweepProcs: got %d, want %d", got, original)
+	}
+
+	if len(b.results) != len(procs) {
+		t.Fatalf("got %d results, want %d", len(b.results), len(procs))
+	}
+
+	for i, p := range procs {
+		want := fmt.Sprintf("work-%d", p)
+		if b.results[i].Name != want {
+			t.Errorf("result[%d].Name = %q, want %q", i, b.results[i].Name, want)
+		}
+	}
+}
+
+// TestTimerExcludesStoppedSegment verifies that time spent between
+// StopTimer and StartTimer is excluded from the resulting measurement, so
+// setup cost doesn't inflate the benchmark.
+func TestTimerExcludesStoppedSegment(t *testing.T) {
+	const iterations = 3
+	const stoppedSleep = 50 * time.Millisecond
+
+	measurements := runIterations(func(t *Timer) {
+		t.StopTimer()
+		time.Sleep(stoppedSleep)
+		t.StartTimer()
+	}, iterations, nil, nil)
+
+	for i, m := range measurements {
+		if m.duration >= stoppedSleep {
+			t.Errorf("measurements[%d].duration = %v, want well under the %v excluded sleep (setup cost leaked in)", i, m.duration, stoppedSleep)
+		}
+	}
+}
+
+func TestTimerResetDiscardsAccumulatedState(t *testing.T) {
+	timer := &Timer{}
+	timer.StartTimer()
+	time.Sleep(5 * time.Millisecond)
+	timer.StopTimer()
+
+	if timer.elapsed == 0 {
+		t.Fatal("expected nonzero elapsed before ResetTimer")
+	}
+
+	timer.StartTimer()
+	timer.ResetTimer()
+
+	if !timer.running {
+		t.Error("ResetTimer should not stop a running timer")
+	}
+	if timer.elapsed != 0 || timer.memoryDelta != 0 || timer.allocCount != 0 {
+		t.Errorf("ResetTimer should discard accumulated state, got elapsed=%v memoryDelta=%d allocCount=%d",
+			timer.elapsed, timer.memoryDelta, timer.allocCount)
+	}
+
+	timer.StopTimer()
+	if timer.running {
+		t.Error("timer should be stopped after StopTimer")
+	}
+}
+
+func TestTimerResetWhenStopped(t *testing.T) {
+	timer := &Timer{}
+	timer.StartTimer()
+	timer.StopTimer()
+
+	timer.ResetTimer()
+	if timer.running {
+		t.Error("ResetTimer should not start a stopped timer")
+	}
+}
+
+// TestProfileCapture is a smoke test: with all three WithXProfile options
+// set, RunT/Run should write a CPU, heap, and block profile to dir, each
+// recorded on the result and each a non-empty gzip-compressed pprof blob.
+func TestProfileCapture(t *testing.T) {
+	dir := t.TempDir()
+
+	b := New().WithCPUProfile(dir).WithMemProfile(dir).WithBlockProfile(dir, 1)
+	b.Run("profiled", func() {
+		_ = make([]byte, 1024)
+	}, 10)
+
+	if len(b.results) != 1 {
+		t.Fatalf("got %d results, want 1", len(b.results))
+	}
+	result := b.results[0]
+
+	checkProfileFile(t, result.CPUProfilePath, filepath.Join(dir, "profiled.cpu.pprof"))
+	checkProfileFile(t, result.MemProfilePath, filepath.Join(dir, "profiled.heap.pprof"))
+	checkProfileFile(t, result.BlockProfilePath, filepath.Join(dir, "profiled.block.pprof"))
+}
+
+// checkProfileFile asserts gotPath matches wantPath and that the file it
+// names is a non-empty, gzip-compressed pprof profile (the format
+// runtime/pprof writes in its default, non-debug mode).
+func checkProfileFile(t *testing.T, gotPath, wantPath string) {
+	t.Helper()
+
+	if gotPath != wantPath {
+		t.Fatalf("profile path = %q, want %q", gotPath, wantPath)
+	}
+
+	data, err := os.ReadFile(gotPath)
+	if err != nil {
+		t.Fatalf("reading %s: %v", gotPath, err)
+	}
+	if len(data) < 2 {
+		t.Fatalf("%s is empty", gotPath)
+	}
+	if data[0] != 0x1f || data[1] != 0x8b {
+		t.Errorf("%s doesn't look like a gzip-compressed pprof profile (missing gzip magic bytes)", gotPath)
+	}
+}